@@ -3,24 +3,48 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 )
 
 // Configuration
 var (
-	apiURL      = "http://localhost:8000/predict"
-	testDuration = 60 * time.Second
-	numWorkers   = 10
+	apiURL              = "http://localhost:8000/predict"
+	testDuration        = 60 * time.Second
+	numWorkers          = 10
+	loadMode            = "closed"
+	targetRPS           = 0.0
+	metricsAddr         = ""
+	pushgatewayURL      = ""
+	payloadsFile        = ""
+	payloadTemplateFile = ""
+	payloadSeed         = int64(1)
 )
 
+// loadMode values:
+//   closed - each worker waits for a response before firing its next request
+//            (measures max throughput, but hides queueing under load)
+//   open   - requests arrive on their own schedule (Poisson process at
+//            targetRPS) independent of how fast responses come back, so
+//            tail latency reflects a fixed offered load instead of
+//            coordinated omission
+
 // Sample payloads for testing
 var samplePayloads = []map[string]interface{}{
 	{
@@ -80,26 +104,459 @@ var samplePayloads = []map[string]interface{}{
 	},
 }
 
+// payloadProvider returns the next payload a worker should send. The
+// built-in samples, -payloads files, and -payload-template all implement it
+// the same way from the worker's point of view.
+type payloadProvider func() map[string]interface{}
+
+// payloadFieldDist describes how to randomize one field of a -payload-template
+// payload. Only the distribution's own parameters need to be set: "uniform"
+// draws from [Min, Max), "bernoulli" emits 1 with probability P and 0
+// otherwise.
+type payloadFieldDist struct {
+	Dist string  `json:"dist"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	P    float64 `json:"p"`
+}
+
+type payloadTemplate map[string]payloadFieldDist
+
+func loadPayloadTemplate(path string) (payloadTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading payload template: %w", err)
+	}
+	var tmpl payloadTemplate
+	if err := json.Unmarshal(data, &tmpl); err != nil {
+		return nil, fmt.Errorf("parsing payload template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// sample draws one randomized payload using rng, which callers seed
+// per-worker so that a run is reproducible field-by-field under -seed.
+func (t payloadTemplate) sample(rng *rand.Rand) map[string]interface{} {
+	payload := make(map[string]interface{}, len(t))
+	for field, dist := range t {
+		switch dist.Dist {
+		case "uniform":
+			payload[field] = dist.Min + rng.Float64()*(dist.Max-dist.Min)
+		case "bernoulli":
+			if rng.Float64() < dist.P {
+				payload[field] = 1
+			} else {
+				payload[field] = 0
+			}
+		default:
+			payload[field] = 0
+		}
+	}
+	return payload
+}
+
+// streamSamplePayloads feeds the built-in samplePayloads into ch on an
+// endless cycle, the same round-robin order the old in-worker index used.
+func streamSamplePayloads(ch chan<- map[string]interface{}, stopChan <-chan struct{}) {
+	defer close(ch)
+	i := 0
+	for {
+		select {
+		case <-stopChan:
+			return
+		case ch <- samplePayloads[i%len(samplePayloads)]:
+			i++
+		}
+	}
+}
+
+// streamPayloadFile feeds parsed rows from a .jsonl or .csv file into ch,
+// looping back to the start of the file if the test runs longer than the
+// file has rows. Running it in its own goroutine keeps file I/O off the
+// request-dispatch path. On a read/parse error it closes ch and returns
+// without waiting for stopChan, leaving the channel closed while stopChan is
+// still open — the signal channelPayloadProvider's closed-channel check uses to tell a
+// genuine producer failure apart from ordinary end-of-test shutdown.
+func streamPayloadFile(path string, ch chan<- map[string]interface{}, stopChan <-chan struct{}) {
+	defer close(ch)
+	ext := strings.ToLower(filepath.Ext(path))
+
+	for {
+		if err := streamPayloadFileOnce(path, ext, ch, stopChan); err != nil {
+			fmt.Printf("⚠️  Error reading payload file %s: %v\n", path, err)
+			return
+		}
+		select {
+		case <-stopChan:
+			return
+		default:
+		}
+	}
+}
+
+func streamPayloadFileOnce(path, ext string, ch chan<- map[string]interface{}, stopChan <-chan struct{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch ext {
+	case ".jsonl":
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			var payload map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &payload); err != nil {
+				return fmt.Errorf("parsing jsonl line: %w", err)
+			}
+			select {
+			case ch <- payload:
+			case <-stopChan:
+				return nil
+			}
+		}
+		return scanner.Err()
+	case ".csv":
+		reader := csv.NewReader(f)
+		header, err := reader.Read()
+		if err != nil {
+			return fmt.Errorf("reading csv header: %w", err)
+		}
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("reading csv row: %w", err)
+			}
+			payload := make(map[string]interface{}, len(header))
+			for i, col := range header {
+				if i >= len(record) {
+					continue
+				}
+				if num, err := strconv.ParseFloat(record[i], 64); err == nil {
+					payload[col] = num
+				} else {
+					payload[col] = record[i]
+				}
+			}
+			select {
+			case ch <- payload:
+			case <-stopChan:
+				return nil
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported payload file extension %q (expected .jsonl or .csv)", ext)
+	}
+}
+
+// channelPayloadProvider reads payloads from a producer-fed channel. A
+// receive on a closed channel returns the zero value instantly rather than
+// blocking, so on close it checks stopChan to tell two cases apart: if
+// stopChan is already closed, this is an ordinary end-of-test shutdown and
+// the zero value is discarded by the caller loop's own stopChan check; if
+// stopChan is still open, the producer (e.g. a -payloads file reader) died
+// unexpectedly, and running with the zero-value payload for the rest of the
+// test would silently corrupt every remaining result, so it exits fatally
+// instead.
+func channelPayloadProvider(ch <-chan map[string]interface{}, stopChan <-chan struct{}) func() map[string]interface{} {
+	return func() map[string]interface{} {
+		payload, ok := <-ch
+		if !ok {
+			select {
+			case <-stopChan:
+			default:
+				fmt.Println("❌ Payload source failed unexpectedly; aborting test")
+				os.Exit(1)
+			}
+		}
+		return payload
+	}
+}
+
+// newPayloadProviderFactory picks the configured payload source and returns
+// a factory that hands each worker its own payloadProvider. File and
+// built-in-sample sources share one channel-fed pipeline (a single producer
+// goroutine owns the file I/O); the template source instead gives each
+// worker a payloadProvider backed by its own rand.Rand, seeded from
+// payloadSeed and the worker's index, so a run is reproducible per worker.
+func newPayloadProviderFactory(stopChan <-chan struct{}) (func(workerID int) payloadProvider, error) {
+	switch {
+	case payloadTemplateFile != "":
+		tmpl, err := loadPayloadTemplate(payloadTemplateFile)
+		if err != nil {
+			return nil, err
+		}
+		return func(workerID int) payloadProvider {
+			rng := rand.New(rand.NewSource(payloadSeed + int64(workerID)))
+			return func() map[string]interface{} {
+				return tmpl.sample(rng)
+			}
+		}, nil
+
+	case payloadsFile != "":
+		ch := make(chan map[string]interface{}, numWorkers*4)
+		go streamPayloadFile(payloadsFile, ch, stopChan)
+		next := channelPayloadProvider(ch, stopChan)
+		return func(workerID int) payloadProvider {
+			return next
+		}, nil
+
+	default:
+		ch := make(chan map[string]interface{}, numWorkers*4)
+		go streamSamplePayloads(ch, stopChan)
+		next := channelPayloadProvider(ch, stopChan)
+		return func(workerID int) payloadProvider {
+			return next
+		}, nil
+	}
+}
+
+// Histogram bucket layout: base-2 logarithmic buckets between histMinLatency
+// and histMaxLatency, subdivided linearly within each power-of-two so that
+// percentiles can be interpolated without keeping every sample. This trades a
+// small, fixed amount of memory (numHistBuckets counters) and a bounded
+// interpolation error for the unbounded growth of the old responseTimes
+// slice, and lets multi-hour runs finish without OOM.
+const (
+	histMinLatency            = 100 * time.Microsecond
+	histMaxLatency            = 60 * time.Second
+	histSubBucketsPerPowerOf2 = 100
+)
+
+var numHistBuckets = int(math.Ceil(math.Log2(float64(histMaxLatency)/float64(histMinLatency))*histSubBucketsPerPowerOf2)) + 1
+
+// Histogram is a fixed-memory, concurrency-safe latency histogram. Samples
+// are recorded with a single atomic increment per bucket; percentiles are
+// read by walking cumulative bucket counts and linearly interpolating within
+// the bucket that contains the target rank.
+type Histogram struct {
+	buckets []int64 // atomic counters, one per bucket
+	count   int64   // atomic total sample count
+	sumNs   int64   // atomic sum of all samples, for the mean
+	minNs   int64   // atomic
+	maxNs   int64   // atomic
+}
+
+func newHistogram() *Histogram {
+	return &Histogram{
+		buckets: make([]int64, numHistBuckets),
+		minNs:   math.MaxInt64,
+	}
+}
+
+func histBucketIndex(ns int64) int {
+	if ns < int64(histMinLatency) {
+		ns = int64(histMinLatency)
+	}
+	if ns > int64(histMaxLatency) {
+		ns = int64(histMaxLatency)
+	}
+	power := math.Log2(float64(ns) / float64(histMinLatency))
+	idx := int(power * histSubBucketsPerPowerOf2)
+	if idx >= numHistBuckets {
+		idx = numHistBuckets - 1
+	}
+	return idx
+}
+
+// histBucketBound returns the lower edge of bucket idx, in nanoseconds.
+func histBucketBound(idx int) float64 {
+	power := float64(idx) / histSubBucketsPerPowerOf2
+	return float64(histMinLatency) * math.Pow(2, power)
+}
+
+func (h *Histogram) Record(d time.Duration) {
+	ns := d.Nanoseconds()
+	atomic.AddInt64(&h.count, 1)
+	atomic.AddInt64(&h.sumNs, ns)
+	for {
+		cur := atomic.LoadInt64(&h.minNs)
+		if ns >= cur || atomic.CompareAndSwapInt64(&h.minNs, cur, ns) {
+			break
+		}
+	}
+	for {
+		cur := atomic.LoadInt64(&h.maxNs)
+		if ns <= cur || atomic.CompareAndSwapInt64(&h.maxNs, cur, ns) {
+			break
+		}
+	}
+	atomic.AddInt64(&h.buckets[histBucketIndex(ns)], 1)
+}
+
+// Percentile returns an interpolated estimate of the p-th percentile
+// (0 < p <= 100), or 0 if no samples have been recorded.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	return percentileFromBuckets(h.bucketCounts(), p)
+}
+
+// bucketCounts returns a point-in-time copy of the per-bucket counters, for
+// callers (like the live progress reporter) that need to diff two snapshots
+// to get windowed rather than cumulative percentiles.
+func (h *Histogram) bucketCounts() []int64 {
+	counts := make([]int64, len(h.buckets))
+	for idx := range h.buckets {
+		counts[idx] = atomic.LoadInt64(&h.buckets[idx])
+	}
+	return counts
+}
+
+// percentileFromBuckets computes the p-th percentile (0 < p <= 100) from a
+// snapshot of bucket counts using the same layout as histBucketIndex.
+func percentileFromBuckets(buckets []int64, p float64) time.Duration {
+	var total int64
+	for _, c := range buckets {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+	target := int64(math.Ceil(p / 100 * float64(total)))
+	if target < 1 {
+		target = 1
+	}
+
+	var cumulative int64
+	for idx, c := range buckets {
+		if cumulative+c >= target {
+			lower := histBucketBound(idx)
+			upper := histBucketBound(idx + 1)
+			if c <= 0 {
+				return time.Duration(lower)
+			}
+			frac := float64(target-cumulative-1) / float64(c)
+			return time.Duration(lower + (upper-lower)*frac)
+		}
+		cumulative += c
+	}
+	return histMaxLatency
+}
+
+func (h *Histogram) Count() int64 { return atomic.LoadInt64(&h.count) }
+
+func (h *Histogram) Mean() time.Duration {
+	count := atomic.LoadInt64(&h.count)
+	if count == 0 {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&h.sumNs) / count)
+}
+
+func (h *Histogram) Min() time.Duration {
+	if atomic.LoadInt64(&h.count) == 0 {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&h.minNs))
+}
+
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(atomic.LoadInt64(&h.maxNs))
+}
+
+func (h *Histogram) Sum() time.Duration {
+	return time.Duration(atomic.LoadInt64(&h.sumNs))
+}
+
+// CountLE returns how many recorded samples are <= threshold, by summing
+// buckets up to and including the one threshold falls in. This is what a
+// Prometheus histogram's cumulative `le` buckets need.
+func (h *Histogram) CountLE(threshold time.Duration) int64 {
+	idx := histBucketIndex(threshold.Nanoseconds())
+	var sum int64
+	for i, c := range h.bucketCounts() {
+		if i > idx {
+			break
+		}
+		sum += c
+	}
+	return sum
+}
+
+// snapshot returns a JSON-friendly view of the histogram, including the
+// bucket layout, so that results from separate runs can be merged bucket by
+// bucket as long as the layout constants match.
+func (h *Histogram) snapshot() map[string]interface{} {
+	buckets := make([]int64, len(h.buckets))
+	for i := range h.buckets {
+		buckets[i] = atomic.LoadInt64(&h.buckets[i])
+	}
+	return map[string]interface{}{
+		"count":                      h.Count(),
+		"min_ms":                     float64(h.Min().Nanoseconds()) / 1e6,
+		"max_ms":                     float64(h.Max().Nanoseconds()) / 1e6,
+		"mean_ms":                    float64(h.Mean().Nanoseconds()) / 1e6,
+		"p50_ms":                     float64(h.Percentile(50).Nanoseconds()) / 1e6,
+		"p90_ms":                     float64(h.Percentile(90).Nanoseconds()) / 1e6,
+		"p95_ms":                     float64(h.Percentile(95).Nanoseconds()) / 1e6,
+		"p99_ms":                     float64(h.Percentile(99).Nanoseconds()) / 1e6,
+		"p99.9_ms":                   float64(h.Percentile(99.9).Nanoseconds()) / 1e6,
+		"min_latency_ns":             int64(histMinLatency),
+		"max_latency_ns":             int64(histMaxLatency),
+		"sub_buckets_per_power_of_2": histSubBucketsPerPowerOf2,
+		"buckets":                    buckets,
+	}
+}
+
 // Statistics tracking
 type Stats struct {
 	successfulRequests int64
 	failedRequests     int64
-	responseTimes      []time.Duration
-	responseTimesMutex sync.Mutex
+	activeWorkers      int64
+	responseTimes      *Histogram
+	queueWaitTimes     *Histogram
 	errors             []string
 	errorsMutex        sync.Mutex
 	startTime          time.Time
 	endTime            time.Time
+	// measureStart is when the steady-state measurement window begins.
+	// Samples recorded before it (during -rampup) are dropped so ramp-up
+	// traffic doesn't skew the final percentile report. Equal to startTime
+	// when -rampup is 0.
+	measureStart time.Time
+}
+
+func newStats() *Stats {
+	return &Stats{
+		responseTimes:  newHistogram(),
+		queueWaitTimes: newHistogram(),
+	}
+}
+
+// inMeasurementWindow reports whether now falls after ramp-up, i.e. whether
+// a sample taken now should count toward the reported results.
+func (s *Stats) inMeasurementWindow() bool {
+	return !time.Now().Before(s.measureStart)
 }
 
 func (s *Stats) addSuccess(responseTime time.Duration) {
+	if !s.inMeasurementWindow() {
+		return
+	}
 	atomic.AddInt64(&s.successfulRequests, 1)
-	s.responseTimesMutex.Lock()
-	s.responseTimes = append(s.responseTimes, responseTime)
-	s.responseTimesMutex.Unlock()
+	s.responseTimes.Record(responseTime)
+}
+
+// addQueueWait records how long a request sat in the dispatch queue before a
+// worker picked it up, measured separately from server response time so that
+// pool saturation in open-loop mode is visible on its own.
+func (s *Stats) addQueueWait(waitTime time.Duration) {
+	if !s.inMeasurementWindow() {
+		return
+	}
+	s.queueWaitTimes.Record(waitTime)
 }
 
 func (s *Stats) addFailure(errorMsg string) {
+	if !s.inMeasurementWindow() {
+		return
+	}
 	atomic.AddInt64(&s.failedRequests, 1)
 	s.errorsMutex.Lock()
 	if len(s.errors) < 10 {
@@ -110,7 +567,16 @@ func (s *Stats) addFailure(errorMsg string) {
 
 func (s *Stats) getResults() map[string]interface{} {
 	totalRequests := s.successfulRequests + s.failedRequests
-	duration := s.endTime.Sub(s.startTime).Seconds()
+	// Use measureStart rather than startTime: samples before measureStart
+	// (the -rampup window) are excluded by inMeasurementWindow, so the
+	// denominator here must cover the same window as the numerator.
+	windowStart := s.startTime
+	if s.measureStart.After(windowStart) {
+		windowStart = s.measureStart
+	}
+	// A shutdown (Ctrl-C) during -rampup ends the run before windowStart is
+	// even reached; clamp rather than report a negative duration.
+	duration := math.Max(0, s.endTime.Sub(windowStart).Seconds())
 
 	results := map[string]interface{}{
 		"total_requests":      totalRequests,
@@ -129,32 +595,35 @@ func (s *Stats) getResults() map[string]interface{} {
 		results["successful_rps"] = 0.0
 	}
 
-	s.responseTimesMutex.Lock()
-	if len(s.responseTimes) > 0 {
-		var total time.Duration
-		min := s.responseTimes[0]
-		max := s.responseTimes[0]
-		for _, rt := range s.responseTimes {
-			total += rt
-			if rt < min {
-				min = rt
-			}
-			if rt > max {
-				max = rt
-			}
-		}
-		avg := total / time.Duration(len(s.responseTimes))
-		results["avg_response_time_ms"] = float64(avg.Nanoseconds()) / 1e6
-		results["min_response_time_ms"] = float64(min.Nanoseconds()) / 1e6
-		results["max_response_time_ms"] = float64(max.Nanoseconds()) / 1e6
-		results["median_response_time_ms"] = float64(s.responseTimes[len(s.responseTimes)/2].Nanoseconds()) / 1e6
+	if s.responseTimes.Count() > 0 {
+		results["avg_response_time_ms"] = float64(s.responseTimes.Mean().Nanoseconds()) / 1e6
+		results["min_response_time_ms"] = float64(s.responseTimes.Min().Nanoseconds()) / 1e6
+		results["max_response_time_ms"] = float64(s.responseTimes.Max().Nanoseconds()) / 1e6
+		results["median_response_time_ms"] = float64(s.responseTimes.Percentile(50).Nanoseconds()) / 1e6
+		results["p90_response_time_ms"] = float64(s.responseTimes.Percentile(90).Nanoseconds()) / 1e6
+		results["p95_response_time_ms"] = float64(s.responseTimes.Percentile(95).Nanoseconds()) / 1e6
+		results["p99_response_time_ms"] = float64(s.responseTimes.Percentile(99).Nanoseconds()) / 1e6
+		results["p99.9_response_time_ms"] = float64(s.responseTimes.Percentile(99.9).Nanoseconds()) / 1e6
 	} else {
 		results["avg_response_time_ms"] = 0.0
 		results["min_response_time_ms"] = 0.0
 		results["max_response_time_ms"] = 0.0
 		results["median_response_time_ms"] = 0.0
+		results["p90_response_time_ms"] = 0.0
+		results["p95_response_time_ms"] = 0.0
+		results["p99_response_time_ms"] = 0.0
+		results["p99.9_response_time_ms"] = 0.0
+	}
+	results["response_time_histogram"] = s.responseTimes.snapshot()
+
+	if s.queueWaitTimes.Count() > 0 {
+		results["avg_queue_wait_ms"] = float64(s.queueWaitTimes.Mean().Nanoseconds()) / 1e6
+		results["p99_queue_wait_ms"] = float64(s.queueWaitTimes.Percentile(99).Nanoseconds()) / 1e6
+	} else {
+		results["avg_queue_wait_ms"] = 0.0
+		results["p99_queue_wait_ms"] = 0.0
 	}
-	s.responseTimesMutex.Unlock()
+	results["queue_wait_histogram"] = s.queueWaitTimes.snapshot()
 
 	return results
 }
@@ -221,21 +690,41 @@ func makeRequest(client *http.Client, payload map[string]interface{}) (bool, tim
 	return false, elapsed, fmt.Sprintf("HTTP %d: %s", resp.StatusCode, bodyStr)
 }
 
-func worker(client *http.Client, stats *Stats, stopChan <-chan struct{}, wg *sync.WaitGroup) {
+// worker runs the closed-loop: it waits for each response before firing the
+// next request, so offered load is whatever the server can keep up with. If
+// startDelay is non-zero (ramping up worker count under -rampup), it waits
+// that long before joining the pool so the active worker count scales
+// linearly from 0 to numWorkers over the ramp window.
+func worker(client *http.Client, stats *Stats, stopChan <-chan struct{}, wg *sync.WaitGroup, nextPayload payloadProvider, startDelay time.Duration) {
 	defer wg.Done()
-	
-	payloadIndex := 0
-	
+
+	if startDelay > 0 {
+		select {
+		case <-time.After(startDelay):
+		case <-stopChan:
+			return
+		}
+	}
+
+	atomic.AddInt64(&stats.activeWorkers, 1)
+	defer atomic.AddInt64(&stats.activeWorkers, -1)
+
 	for {
 		select {
 		case <-stopChan:
 			return
 		default:
-			payload := samplePayloads[payloadIndex%len(samplePayloads)]
-			payloadIndex++
-			
+			payload := nextPayload()
+			select {
+			case <-stopChan:
+				// Shutdown began while waiting for a payload, which may be
+				// the zero value from a closed channel; don't send it.
+				return
+			default:
+			}
+
 			success, responseTime, errorMsg := makeRequest(client, payload)
-			
+
 			if success {
 				stats.addSuccess(responseTime)
 			} else {
@@ -245,6 +734,347 @@ func worker(client *http.Client, stats *Stats, stopChan <-chan struct{}, wg *syn
 	}
 }
 
+// job is one request dispatched by the open-loop arrival process; submitTime
+// lets the worker that eventually picks it up measure queue-wait separately
+// from server response time.
+type job struct {
+	payload    map[string]interface{}
+	submitTime time.Time
+}
+
+// openLoopWorker drains jobs from a shared queue independent of its own
+// response times, so the offered rate is set entirely by the arrival process.
+func openLoopWorker(client *http.Client, stats *Stats, jobs <-chan job, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	atomic.AddInt64(&stats.activeWorkers, 1)
+	defer atomic.AddInt64(&stats.activeWorkers, -1)
+
+	for j := range jobs {
+		stats.addQueueWait(time.Since(j.submitTime))
+
+		success, responseTime, errorMsg := makeRequest(client, j.payload)
+
+		if success {
+			stats.addSuccess(responseTime)
+		} else {
+			stats.addFailure(errorMsg)
+		}
+	}
+}
+
+// currentTargetRPS returns the offered rate at time.Now(): targetRPS once
+// past rampStart+rampupDuration, linearly scaled from 0 before that. With no
+// rampup (rampupDuration == 0) it's always targetRPS.
+func currentTargetRPS(rampStart time.Time, rampupDuration time.Duration) float64 {
+	if rampupDuration <= 0 {
+		return targetRPS
+	}
+	frac := float64(time.Since(rampStart)) / float64(rampupDuration)
+	if frac >= 1 {
+		return targetRPS
+	}
+	if frac < 0 {
+		frac = 0
+	}
+	return targetRPS * frac
+}
+
+// rampPollInterval bounds how long openLoopDispatcher will commit to a single
+// exponential draw while the offered rate is still ramping up. Without this,
+// a draw made from a near-zero instantaneous rate early in the ramp window
+// (mean interval = 1/rate can be tens of seconds even a few milliseconds in)
+// can stall the dispatcher for far longer than the ramp window itself.
+const rampPollInterval = 50 * time.Millisecond
+
+// openLoopDispatcher generates arrivals as a Poisson process at targetRPS,
+// pushing each onto the bounded jobs queue for the worker pool to pick up.
+// Inter-arrival times are drawn from an exponential distribution, which is
+// what a Poisson arrival process reduces to between events.
+func openLoopDispatcher(jobs chan<- job, stopChan <-chan struct{}, rng *rand.Rand, nextPayload payloadProvider, rampStart time.Time, rampupDuration time.Duration) {
+	defer close(jobs)
+
+	for {
+		rate := currentTargetRPS(rampStart, rampupDuration)
+		if rate <= 0 {
+			// Still ramping up from zero; nothing to dispatch yet.
+			select {
+			case <-stopChan:
+				return
+			case <-time.After(rampPollInterval):
+				continue
+			}
+		}
+
+		meanInterval := time.Duration(float64(time.Second) / rate)
+		interval := time.Duration(rng.ExpFloat64() * float64(meanInterval))
+
+		// While still ramping, cap the wait so the rate gets re-sampled
+		// often instead of committing to one draw computed from a rate
+		// that's about to be stale (it's rising throughout the window).
+		ramping := rampupDuration > 0 && time.Since(rampStart) < rampupDuration
+		wait := interval
+		if ramping && wait > rampPollInterval {
+			wait = rampPollInterval
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-stopChan:
+			timer.Stop()
+			return
+		case <-timer.C:
+			if wait < interval {
+				// The cap fired before the drawn interval elapsed; go
+				// re-sample the rate rather than dispatching early.
+				continue
+			}
+
+			payload := nextPayload()
+			select {
+			case <-stopChan:
+				return
+			default:
+			}
+
+			select {
+			case jobs <- job{payload: payload, submitTime: time.Now()}:
+			case <-stopChan:
+				return
+			}
+		}
+	}
+}
+
+// reportSnapshot is a point-in-time read of the counters the live reporter
+// diffs against the previous tick to get windowed (rather than cumulative)
+// figures.
+type reportSnapshot struct {
+	takenAt         time.Time
+	successes       int64
+	failures        int64
+	responseBuckets []int64
+}
+
+func takeReportSnapshot(stats *Stats) reportSnapshot {
+	return reportSnapshot{
+		takenAt:         time.Now(),
+		successes:       atomic.LoadInt64(&stats.successfulRequests),
+		failures:        atomic.LoadInt64(&stats.failedRequests),
+		responseBuckets: stats.responseTimes.bucketCounts(),
+	}
+}
+
+// diffBuckets returns cur-prev bucket-by-bucket; both slices share the same
+// fixed layout so this is always well-defined.
+func diffBuckets(prev, cur []int64) []int64 {
+	diff := make([]int64, len(cur))
+	for i := range cur {
+		diff[i] = cur[i] - prev[i]
+	}
+	return diff
+}
+
+// humanizeCount renders large counts the way long-running load tools do
+// ("12.3K", "4.1M") instead of a raw digit string, so a reporter line stays
+// readable once a run has pushed millions of requests.
+func humanizeCount(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fK", float64(n)/1_000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+// reporter prints incremental progress every interval: elapsed time,
+// requests seen in the last window, windowed RPS, and windowed p50/p95/p99
+// latency. It gives operators visibility into warm-up behavior and
+// steady-state drift instead of only seeing a final aggregate after
+// testDuration.
+func reporter(stats *Stats, interval time.Duration, stopChan <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	prev := takeReportSnapshot(stats)
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+			cur := takeReportSnapshot(stats)
+
+			windowRequests := (cur.successes + cur.failures) - (prev.successes + prev.failures)
+			windowSeconds := cur.takenAt.Sub(prev.takenAt).Seconds()
+			windowRPS := 0.0
+			if windowSeconds > 0 {
+				windowRPS = float64(windowRequests) / windowSeconds
+			}
+
+			windowBuckets := diffBuckets(prev.responseBuckets, cur.responseBuckets)
+			p50 := percentileFromBuckets(windowBuckets, 50)
+			p95 := percentileFromBuckets(windowBuckets, 95)
+			p99 := percentileFromBuckets(windowBuckets, 99)
+
+			fmt.Printf(
+				"[%6.0fs elapsed] requests: %s (+%s in last %.0fs, %.1f rps) | p50: %.1fms p95: %.1fms p99: %.1fms\n",
+				cur.takenAt.Sub(stats.startTime).Seconds(),
+				humanizeCount(cur.successes+cur.failures),
+				humanizeCount(windowRequests),
+				windowSeconds,
+				windowRPS,
+				float64(p50.Nanoseconds())/1e6,
+				float64(p95.Nanoseconds())/1e6,
+				float64(p99.Nanoseconds())/1e6,
+			)
+
+			prev = cur
+		}
+	}
+}
+
+// promHistogramBuckets are the `le` boundaries (in seconds) used when
+// rendering loadgen_request_duration_seconds as a Prometheus histogram.
+// These are coarser than the internal Histogram's ~2000 log-buckets, which
+// is fine: Prometheus histograms are meant to be scraped and aggregated
+// across many runs, not to reproduce exact percentiles on their own.
+var promHistogramBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// renderPrometheusMetrics formats the running Stats in Prometheus text
+// exposition format so the load generator can be scraped alongside the API
+// under test, letting client-side latency be correlated with server-side
+// CPU/memory in one Grafana dashboard.
+func renderPrometheusMetrics(stats *Stats) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# HELP loadgen_requests_total Total requests issued, by outcome\n")
+	fmt.Fprintf(&b, "# TYPE loadgen_requests_total counter\n")
+	fmt.Fprintf(&b, "loadgen_requests_total{status=\"success\"} %d\n", atomic.LoadInt64(&stats.successfulRequests))
+	fmt.Fprintf(&b, "loadgen_requests_total{status=\"failure\"} %d\n", atomic.LoadInt64(&stats.failedRequests))
+
+	fmt.Fprintf(&b, "# HELP loadgen_active_workers Number of workers currently dispatching requests\n")
+	fmt.Fprintf(&b, "# TYPE loadgen_active_workers gauge\n")
+	fmt.Fprintf(&b, "loadgen_active_workers %d\n", atomic.LoadInt64(&stats.activeWorkers))
+
+	fmt.Fprintf(&b, "# HELP loadgen_offered_rps Target requests per second for -mode=open (0 in closed-loop mode)\n")
+	fmt.Fprintf(&b, "# TYPE loadgen_offered_rps gauge\n")
+	offeredRPS := 0.0
+	if loadMode == "open" {
+		offeredRPS = targetRPS
+	}
+	fmt.Fprintf(&b, "loadgen_offered_rps %g\n", offeredRPS)
+
+	writePrometheusHistogram(&b, "loadgen_request_duration_seconds", "Request duration in seconds", stats.responseTimes)
+
+	return b.String()
+}
+
+func writePrometheusHistogram(b *strings.Builder, name, help string, h *Histogram) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s histogram\n", name)
+	for _, le := range promHistogramBuckets {
+		threshold := time.Duration(le * float64(time.Second))
+		fmt.Fprintf(b, "%s_bucket{le=\"%g\"} %d\n", name, le, h.CountLE(threshold))
+	}
+	fmt.Fprintf(b, "%s_bucket{le=\"+Inf\"} %d\n", name, h.Count())
+	fmt.Fprintf(b, "%s_sum %g\n", name, h.Sum().Seconds())
+	fmt.Fprintf(b, "%s_count %d\n", name, h.Count())
+}
+
+// startMetricsServer serves the running Stats as Prometheus metrics on addr
+// until the returned server is shut down.
+func startMetricsServer(addr string, stats *Stats) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		io.WriteString(w, renderPrometheusMetrics(stats))
+	})
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("⚠️  Metrics server error: %v\n", err)
+		}
+	}()
+	return srv
+}
+
+// pushFinalMetrics pushes a final job summary to a Prometheus Pushgateway so
+// a short-lived load test run (which a scraper would otherwise never catch)
+// still leaves a data point behind.
+func pushFinalMetrics(pushgatewayURL string, stats *Stats) error {
+	url := strings.TrimRight(pushgatewayURL, "/") + "/metrics/job/loadgen"
+
+	req, err := http.NewRequest(http.MethodPut, url, strings.NewReader(renderPrometheusMetrics(stats)))
+	if err != nil {
+		return fmt.Errorf("building pushgateway request: %w", err)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("pushing to pushgateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("pushgateway returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// writeResultsJSON persists the full results map, including both latency
+// histograms, so separate runs can be diffed or fed into CI regression
+// checks.
+func writeResultsJSON(path string, results map[string]interface{}) error {
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling results: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// writeResultsCSV persists the flat scalar results (percentiles, RPS,
+// counts, ...) as metric,value rows. The nested histogram snapshots are
+// skipped here; use -out-json to capture those.
+func writeResultsCSV(path string, results map[string]interface{}) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating csv output: %w", err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	defer w.Flush()
+
+	if err := w.Write([]string{"metric", "value"}); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(results))
+	for k := range results {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if _, isNested := results[k].(map[string]interface{}); isNested {
+			continue
+		}
+		if err := w.Write([]string{k, fmt.Sprintf("%v", results[k])}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
 func checkHealth() bool {
 	client := &http.Client{Timeout: 5 * time.Second}
 	resp, err := client.Get("http://localhost:8000/health")
@@ -259,14 +1089,45 @@ func main() {
 	flag.StringVar(&apiURL, "url", "http://localhost:8000/predict", "API endpoint URL")
 	flag.IntVar(&numWorkers, "workers", 10, "Number of concurrent workers")
 	durationFlag := flag.Int("duration", 60, "Test duration in seconds")
+	flag.StringVar(&loadMode, "mode", "closed", "Load mode: closed (wait for response) or open (fixed-rate Poisson arrivals)")
+	flag.Float64Var(&targetRPS, "rps", 0, "Target requests per second for -mode=open (required in open mode)")
+	reportIntervalFlag := flag.Int("report-interval", 0, "Seconds between live progress reports (0 disables)")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "Address to serve Prometheus /metrics on, e.g. :9090 (empty disables)")
+	flag.StringVar(&pushgatewayURL, "pushgateway-url", "", "Pushgateway base URL to push a final job summary to (empty disables)")
+	flag.StringVar(&payloadsFile, "payloads", "", "Payload source file (.jsonl or .csv); defaults to the built-in sample payloads")
+	flag.StringVar(&payloadTemplateFile, "payload-template", "", "JSON template describing per-field distributions for randomized payloads")
+	flag.Int64Var(&payloadSeed, "seed", 1, "Seed for -payload-template's per-worker random generators, for reproducible runs")
+	rampupFlag := flag.Duration("rampup", 0, "Ramp window (e.g. 10s) over which worker count (closed mode) or RPS (open mode) scales linearly from 0; excluded from the final report")
+	outJSONFlag := flag.String("out-json", "", "Write results and the full latency histogram as JSON to this path")
+	outCSVFlag := flag.String("out-csv", "", "Write flat scalar results as CSV to this path")
 	flag.Parse()
-	
+
+	if payloadsFile != "" && payloadTemplateFile != "" {
+		fmt.Println("❌ -payloads and -payload-template are mutually exclusive")
+		return
+	}
+
 	testDuration = time.Duration(*durationFlag) * time.Second
 
+	if loadMode != "closed" && loadMode != "open" {
+		fmt.Printf("❌ Invalid -mode %q: must be \"closed\" or \"open\"\n", loadMode)
+		return
+	}
+	if loadMode == "open" && targetRPS <= 0 {
+		fmt.Println("❌ -mode=open requires -rps > 0")
+		return
+	}
+
 	fmt.Println("Starting load test...")
 	fmt.Printf("API URL: %s\n", apiURL)
 	fmt.Printf("Test duration: %.0f seconds\n", testDuration.Seconds())
-	fmt.Printf("Number of concurrent workers: %d\n", numWorkers)
+	fmt.Printf("Load mode: %s\n", loadMode)
+	if loadMode == "open" {
+		fmt.Printf("Target RPS: %.2f\n", targetRPS)
+		fmt.Printf("Worker pool size: %d\n", numWorkers)
+	} else {
+		fmt.Printf("Number of concurrent workers: %d\n", numWorkers)
+	}
 	fmt.Println("------------------------------------------------------------")
 
 	if !checkHealth() {
@@ -276,11 +1137,24 @@ func main() {
 	}
 	fmt.Println("✓ API health check passed")
 
+	rampupDuration := *rampupFlag
+
 	fmt.Println("\nRunning load test...")
-	fmt.Println("\nWait for", testDuration.Seconds(), "seconds to finish the test...")
-	
-	stats := &Stats{
-		startTime: time.Now(),
+	if rampupDuration > 0 {
+		fmt.Printf("\nWait for %.0f seconds to finish the test (%.0fs ramp-up + %.0fs steady-state)...\n",
+			(rampupDuration + testDuration).Seconds(), rampupDuration.Seconds(), testDuration.Seconds())
+	} else {
+		fmt.Println("\nWait for", testDuration.Seconds(), "seconds to finish the test...")
+	}
+
+	stats := newStats()
+	stats.startTime = time.Now()
+	stats.measureStart = stats.startTime.Add(rampupDuration)
+
+	var metricsServer *http.Server
+	if metricsAddr != "" {
+		metricsServer = startMetricsServer(metricsAddr, stats)
+		fmt.Printf("Serving Prometheus metrics on %s/metrics\n", metricsAddr)
 	}
 	
 	client := &http.Client{
@@ -294,29 +1168,96 @@ func main() {
 	
 	stopChan := make(chan struct{})
 	var wg sync.WaitGroup
-	
-	// Start workers
-	for i := 0; i < numWorkers; i++ {
+
+	newPayload, err := newPayloadProviderFactory(stopChan)
+	if err != nil {
+		fmt.Printf("❌ Failed to set up payload source: %v\n", err)
+		return
+	}
+
+	rampStart := stats.startTime
+
+	if loadMode == "open" {
+		jobs := make(chan job, numWorkers*2)
+		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+		for i := 0; i < numWorkers; i++ {
+			wg.Add(1)
+			go openLoopWorker(client, stats, jobs, &wg)
+		}
+		go openLoopDispatcher(jobs, stopChan, rng, newPayload(numWorkers), rampStart, rampupDuration)
+	} else {
+		// Start workers, staggering their join time under -rampup so the
+		// active worker count scales linearly from 0 to numWorkers.
+		for i := 0; i < numWorkers; i++ {
+			wg.Add(1)
+			startDelay := time.Duration(0)
+			if rampupDuration > 0 {
+				startDelay = rampupDuration * time.Duration(i) / time.Duration(numWorkers)
+			}
+			go worker(client, stats, stopChan, &wg, newPayload(i), startDelay)
+		}
+	}
+
+	if *reportIntervalFlag > 0 {
 		wg.Add(1)
-		go worker(client, stats, stopChan, &wg)
+		go reporter(stats, time.Duration(*reportIntervalFlag)*time.Second, stopChan, &wg)
 	}
-	
-	// Run for test duration
-	time.Sleep(testDuration)
-	
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	// Run for rampupDuration + testDuration so the steady-state measurement
+	// window (after ramp-up) is the full testDuration the user asked for,
+	// rather than testDuration minus whatever ramp-up ate into it. Stop
+	// early and report partial results if interrupted.
+	select {
+	case <-time.After(rampupDuration + testDuration):
+	case sig := <-sigChan:
+		fmt.Printf("\n⚠️  Received %v, shutting down and reporting partial results...\n", sig)
+	}
+	signal.Stop(sigChan)
+
 	// Stop all workers
 	close(stopChan)
 	wg.Wait()
 	
 	stats.endTime = time.Now()
-	
+
+	if pushgatewayURL != "" {
+		if err := pushFinalMetrics(pushgatewayURL, stats); err != nil {
+			fmt.Printf("⚠️  Failed to push final metrics to pushgateway: %v\n", err)
+		} else {
+			fmt.Println("✓ Pushed final metrics to pushgateway")
+		}
+	}
+
+	if metricsServer != nil {
+		metricsServer.Close()
+	}
+
 	// Print results
 	fmt.Println("\n" + "============================================================")
 	fmt.Println("LOAD TEST RESULTS")
 	fmt.Println("============================================================")
 	
 	results := stats.getResults()
-	
+
+	if *outJSONFlag != "" {
+		if err := writeResultsJSON(*outJSONFlag, results); err != nil {
+			fmt.Printf("⚠️  Failed to write -out-json: %v\n", err)
+		} else {
+			fmt.Printf("✓ Wrote results to %s\n", *outJSONFlag)
+		}
+	}
+	if *outCSVFlag != "" {
+		if err := writeResultsCSV(*outCSVFlag, results); err != nil {
+			fmt.Printf("⚠️  Failed to write -out-csv: %v\n", err)
+		} else {
+			fmt.Printf("✓ Wrote results to %s\n", *outCSVFlag)
+		}
+	}
+
 	fmt.Printf("\n📊 Overall Statistics:\n")
 	fmt.Printf("  Total Requests:        %d\n", int64(results["total_requests"].(int64)))
 	fmt.Printf("  Successful Requests:   %d\n", int64(results["successful_requests"].(int64)))
@@ -331,10 +1272,19 @@ func main() {
 	if stats.successfulRequests > 0 {
 		fmt.Printf("\n⏱️  Response Time Statistics:\n")
 		fmt.Printf("  Average Response Time:      %.2f ms\n", results["avg_response_time_ms"].(float64))
-		fmt.Printf("  Median Response Time:       %.2f ms\n", results["median_response_time_ms"].(float64))
+		fmt.Printf("  Median Response Time (p50): %.2f ms\n", results["median_response_time_ms"].(float64))
+		fmt.Printf("  p90 Response Time:          %.2f ms\n", results["p90_response_time_ms"].(float64))
+		fmt.Printf("  p95 Response Time:          %.2f ms\n", results["p95_response_time_ms"].(float64))
+		fmt.Printf("  p99 Response Time:          %.2f ms\n", results["p99_response_time_ms"].(float64))
+		fmt.Printf("  p99.9 Response Time:        %.2f ms\n", results["p99.9_response_time_ms"].(float64))
 		fmt.Printf("  Min Response Time:          %.2f ms\n", results["min_response_time_ms"].(float64))
 		fmt.Printf("  Max Response Time:          %.2f ms\n", results["max_response_time_ms"].(float64))
 	}
+
+	if loadMode == "open" {
+		fmt.Printf("\n⏳ Queue Wait (dispatch to pickup):\n")
+		fmt.Printf("  Average Queue Wait:         %.2f ms\n", results["avg_queue_wait_ms"].(float64))
+	}
 	
 	if len(stats.errors) > 0 {
 		fmt.Printf("\n❌ Error Summary (showing first 10):\n")